@@ -16,12 +16,18 @@ package model
 
 import (
 	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/astaxie/beego/validation"
 	"github.com/goharbor/harbor/src/common/models"
 )
 
+const timeOfDayLayout = "15:04"
+
 // const definition
 const (
 	FilterTypeResource = "resource"
@@ -34,6 +40,16 @@ const (
 	TriggerTypeEventBased = "event_based"
 )
 
+// destNamespaceToken* are the placeholders that can be used in a
+// Policy.DestNamespace/DestNamespaces template.
+const (
+	destNamespaceTokenSrcNamespace = "{src_namespace}"
+	destNamespaceTokenSrcRegistry  = "{src_registry}"
+	destNamespaceTokenDate         = "{date}"
+)
+
+var destNamespaceTokenPattern = regexp.MustCompile(`\{[^{}]*\}`)
+
 // Policy defines the structure of a replication policy
 type Policy struct {
 	ID          int64  `json:"id"`
@@ -47,12 +63,18 @@ type Policy struct {
 	// destination
 	// TODO rename to DstRegistryID
 	DestRegistryID int64 `json:"dest_registry_id"`
-	// Only support two dest namespace modes:
-	// Put all the src resources to the one single dest namespace
-	// or keep namespaces same with the source ones (under this case,
-	// the DestNamespace should be set to empty)
+	// DestNamespace is a template for the dest namespace. It supports the
+	// {src_namespace}, {src_registry} and {date} placeholders, so it can be
+	// left empty to keep namespaces same with the source ones, set to a
+	// literal namespace to put all the src resources there, or set to
+	// something like "backup/{src_namespace}" to derive it from the source.
 	// TODO rename to DstNamespace
 	DestNamespace string `json:"dest_namespace"`
+	// DestNamespaces, if set, fans the replication out to multiple dest
+	// namespaces: each entry is a DestNamespace-style template and every
+	// matching src resource is replicated once per entry. Mutually
+	// exclusive with DestNamespace; Policy.Valid rejects setting both.
+	DestNamespaces []string `json:"dest_namespaces,omitempty"`
 	// Filters
 	Filters []*Filter `json:"filters"`
 	// Trigger
@@ -92,6 +114,24 @@ func (p *Policy) Valid(v *validation.Validation) {
 		}
 	}
 
+	// valid the dest namespace template(s)
+	if len(p.DestNamespace) > 0 && len(p.DestNamespaces) > 0 {
+		v.SetError("dest_namespace, dest_namespaces", "only one of them can be set")
+	}
+	if err := validateDestNamespaceTemplate(p.DestNamespace); err != nil {
+		v.SetError("dest_namespace", err.Error())
+	}
+	for _, namespace := range p.DestNamespaces {
+		if len(namespace) == 0 {
+			v.SetError("dest_namespaces", "cannot contain empty namespace")
+			break
+		}
+		if err := validateDestNamespaceTemplate(namespace); err != nil {
+			v.SetError("dest_namespaces", err.Error())
+			break
+		}
+	}
+
 	// valid the filters
 	for _, filter := range p.Filters {
 		if filter.Type != FilterTypeResource &&
@@ -102,6 +142,30 @@ func (p *Policy) Valid(v *validation.Validation) {
 			break
 		}
 	}
+	for _, filter := range p.Filters {
+		switch filter.MatchMode {
+		case "", FilterMatchModeGlob, FilterMatchModeLiteral:
+		case FilterMatchModeRegex:
+			pattern, ok := filter.Value.(string)
+			if !ok {
+				v.SetError("filters", "the value of a regex filter must be a string pattern")
+				continue
+			}
+			compiled, err := regexp.Compile(pattern)
+			if err != nil {
+				v.SetError("filters", fmt.Sprintf("invalid regex pattern %q: %v", pattern, err))
+				continue
+			}
+			// warm the cache Match lazily fills in, so the common case (Valid
+			// ran just before Match) doesn't pay to recompile the pattern
+			filter.compileMu.Lock()
+			filter.pattern = compiled
+			filter.compiledFrom = pattern
+			filter.compileMu.Unlock()
+		default:
+			v.SetError("filters", "invalid filter match mode")
+		}
+	}
 
 	// valid trigger
 	if p.Trigger != nil {
@@ -114,16 +178,223 @@ func (p *Policy) Valid(v *validation.Validation) {
 			(p.Trigger.Settings == nil || len(p.Trigger.Settings.Cron) == 0) {
 			v.SetError("trigger", fmt.Sprintf("the cron string cannot be empty when the trigger type is %s", TriggerTypeScheduled))
 		}
+		if p.Trigger.Settings != nil {
+			if p.Trigger.Settings.BandwidthLimit < 0 {
+				v.SetError("trigger", "the bandwidth limit cannot be negative")
+			}
+			if window := p.Trigger.Settings.Window; window != nil {
+				if _, err := time.Parse(timeOfDayLayout, window.Start); err != nil {
+					v.SetError("trigger", fmt.Sprintf("invalid window start time %q, the expected format is %q", window.Start, timeOfDayLayout))
+				}
+				if _, err := time.Parse(timeOfDayLayout, window.End); err != nil {
+					v.SetError("trigger", fmt.Sprintf("invalid window end time %q, the expected format is %q", window.End, timeOfDayLayout))
+				}
+				for _, weekday := range window.Weekdays {
+					if weekday < time.Sunday || weekday > time.Saturday {
+						v.SetError("trigger", "invalid weekday in window")
+						break
+					}
+				}
+			}
+		}
 	}
 }
 
+// validateDestNamespaceTemplate checks that every {...} placeholder in
+// template is one of the tokens ResolveDestRepositories knows how to expand.
+func validateDestNamespaceTemplate(template string) error {
+	for _, token := range destNamespaceTokenPattern.FindAllString(template, -1) {
+		switch token {
+		case destNamespaceTokenSrcNamespace, destNamespaceTokenSrcRegistry, destNamespaceTokenDate:
+		default:
+			return fmt.Errorf("unsupported placeholder %q", token)
+		}
+	}
+	return nil
+}
+
+// DestResolveContext carries the values ResolveDestRepositories substitutes
+// for the {src_registry} and {date} placeholders. It's supplied by the
+// caller because the policy itself only knows the source registry ID, not
+// its name, and has no notion of "now".
+type DestResolveContext struct {
+	SrcRegistryName string
+	Date            time.Time
+}
+
+// ResolveDestRepositories expands DestNamespace (or, if set, every entry of
+// DestNamespaces) against srcRepository and returns the concrete destination
+// repository path(s) transfer workers should push to. srcRepository is
+// expected to be in "namespace/repo" form.
+func (p *Policy) ResolveDestRepositories(srcRepository string, ctx DestResolveContext) []string {
+	srcNamespace, name := splitRepository(srcRepository)
+
+	templates := p.DestNamespaces
+	if len(templates) == 0 {
+		templates = []string{p.DestNamespace}
+	}
+
+	repositories := make([]string, 0, len(templates))
+	for _, template := range templates {
+		namespace := expandDestNamespace(template, srcNamespace, ctx)
+		if len(namespace) == 0 {
+			namespace = srcNamespace
+		}
+		if len(namespace) == 0 {
+			// srcRepository had no namespace of its own and the template
+			// didn't supply one either; don't emit a malformed "/name".
+			repositories = append(repositories, name)
+			continue
+		}
+		repositories = append(repositories, namespace+"/"+name)
+	}
+	return repositories
+}
+
+func splitRepository(repository string) (namespace, name string) {
+	i := strings.LastIndex(repository, "/")
+	if i < 0 {
+		return "", repository
+	}
+	return repository[:i], repository[i+1:]
+}
+
+func expandDestNamespace(template, srcNamespace string, ctx DestResolveContext) string {
+	replacer := strings.NewReplacer(
+		destNamespaceTokenSrcNamespace, srcNamespace,
+		destNamespaceTokenSrcRegistry, ctx.SrcRegistryName,
+		destNamespaceTokenDate, ctx.Date.Format("20060102"),
+	)
+	return replacer.Replace(template)
+}
+
 // FilterType represents the type info of the filter.
 type FilterType string
 
+// FilterMatchMode represents how a filter's Value should be interpreted when
+// matching it against a candidate resource.
+type FilterMatchMode string
+
+// const definition
+const (
+	// FilterMatchModeGlob matches Value as a glob pattern, e.g. "library/*".
+	// This is the default when MatchMode is empty, preserving the historical behavior.
+	FilterMatchModeGlob FilterMatchMode = "glob"
+	// FilterMatchModeLiteral matches Value as an exact string.
+	FilterMatchModeLiteral FilterMatchMode = "literal"
+	// FilterMatchModeRegex matches Value, which must be a string, as a regular expression.
+	FilterMatchModeRegex FilterMatchMode = "regex"
+)
+
 // Filter holds the info of the filter
 type Filter struct {
 	Type  FilterType  `json:"type"`
 	Value interface{} `json:"value"`
+	// MatchMode controls how Value is interpreted. Empty is equivalent to
+	// FilterMatchModeGlob.
+	MatchMode FilterMatchMode `json:"match_mode,omitempty"`
+	// Exclude marks this as an exclusion filter: resources it matches are
+	// dropped from the result set rather than kept. Exclusion filters are
+	// applied after all inclusion filters, see Select.
+	Exclude bool `json:"exclude,omitempty"`
+
+	// pattern caches the compiled regular expression for a
+	// FilterMatchModeRegex filter, along with compiledFrom, the Value it was
+	// compiled from. Both are unexported runtime-only state: they don't
+	// survive a JSON round trip (e.g. a DB store/reload), and compiledFrom
+	// lets compiledPattern notice a Filter whose Value was mutated in place
+	// (e.g. re-validated with a new pattern before being persisted) instead
+	// of matching against a stale cached pattern forever.
+	compileMu    sync.Mutex
+	compiledFrom string
+	pattern      *regexp.Regexp
+}
+
+// compiledPattern returns the compiled regular expression for a
+// FilterMatchModeRegex filter, (re)compiling and caching it whenever Value
+// has changed since the last call. An invalid pattern yields a nil result;
+// Policy.Valid is responsible for rejecting those before a filter is ever
+// matched.
+func (f *Filter) compiledPattern() *regexp.Regexp {
+	pattern, ok := f.Value.(string)
+	if !ok {
+		return nil
+	}
+
+	f.compileMu.Lock()
+	defer f.compileMu.Unlock()
+	if f.pattern == nil || f.compiledFrom != pattern {
+		f.pattern, _ = regexp.Compile(pattern)
+		f.compiledFrom = pattern
+	}
+	return f.pattern
+}
+
+// Match reports whether value matches the filter's pattern, according to its
+// MatchMode. It ignores Exclude; callers that need inclusion/exclusion
+// semantics across a set of filters should use Select instead.
+func (f *Filter) Match(value string) bool {
+	switch f.MatchMode {
+	case FilterMatchModeRegex:
+		pattern := f.compiledPattern()
+		if pattern == nil {
+			return false
+		}
+		return pattern.MatchString(value)
+	case FilterMatchModeLiteral:
+		pattern, _ := f.Value.(string)
+		return pattern == value
+	default: // "" or FilterMatchModeGlob
+		pattern, _ := f.Value.(string)
+		matched, _ := filepath.Match(pattern, value)
+		return matched
+	}
+}
+
+// Select returns the subset of resources that pass filters: a resource is
+// kept if it matches at least one inclusion filter (or there are none) and
+// none of the exclusion filters. Exclusion filters are evaluated after
+// inclusion ones, so e.g. a "library/*" include plus a "library/*-debug"
+// exclude replicates everything under library except the debug images.
+func Select(filters []*Filter, resources []string) []string {
+	var includes, excludes []*Filter
+	for _, filter := range filters {
+		if filter.Exclude {
+			excludes = append(excludes, filter)
+		} else {
+			includes = append(includes, filter)
+		}
+	}
+
+	var result []string
+	for _, resource := range resources {
+		if len(includes) > 0 {
+			matched := false
+			for _, filter := range includes {
+				if filter.Match(resource) {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				continue
+			}
+		}
+
+		excluded := false
+		for _, filter := range excludes {
+			if filter.Match(resource) {
+				excluded = true
+				break
+			}
+		}
+		if excluded {
+			continue
+		}
+
+		result = append(result, resource)
+	}
+	return result
 }
 
 // TriggerType represents the type of trigger.
@@ -138,6 +409,63 @@ type Trigger struct {
 // TriggerSettings is the setting about the trigger
 type TriggerSettings struct {
 	Cron string `json:"cron"`
+	// BandwidthLimit caps the transfer rate, in bytes/second, that workers
+	// executing this trigger are allowed to use. Zero or unset means unlimited.
+	BandwidthLimit int64 `json:"bandwidth_limit,omitempty"`
+	// Window restricts the trigger to only fire during the given time range.
+	// A nil Window means the trigger is allowed to run at any time.
+	Window *TriggerWindow `json:"window,omitempty"`
+}
+
+// TriggerWindow defines a recurring time-of-day range, optionally limited to
+// specific weekdays, during which a trigger is allowed to run. Start and End
+// are local times formatted as "15:04". An End before Start is interpreted as
+// spanning midnight.
+type TriggerWindow struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+	// Weekdays restricts the window to the listed days (time.Sunday..time.Saturday).
+	// An empty slice means every day of the week.
+	Weekdays []time.Weekday `json:"weekdays,omitempty"`
+}
+
+// Allows reports whether the trigger window permits running at t. A nil
+// TriggerWindow always allows running. Replication workers should consult
+// this before pulling/pushing blobs for a scheduled or event based trigger.
+func (w *TriggerWindow) Allows(t time.Time) bool {
+	if w == nil {
+		return true
+	}
+	if len(w.Weekdays) > 0 {
+		allowed := false
+		for _, weekday := range w.Weekdays {
+			if weekday == t.Weekday() {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+
+	start, err := time.Parse(timeOfDayLayout, w.Start)
+	if err != nil {
+		return false
+	}
+	end, err := time.Parse(timeOfDayLayout, w.End)
+	if err != nil {
+		return false
+	}
+	now := time.Date(0, 1, 1, t.Hour(), t.Minute(), 0, 0, time.UTC)
+	start = time.Date(0, 1, 1, start.Hour(), start.Minute(), 0, 0, time.UTC)
+	end = time.Date(0, 1, 1, end.Hour(), end.Minute(), 0, 0, time.UTC)
+
+	if end.Before(start) {
+		// the window spans midnight
+		return !now.Before(start) || now.Before(end)
+	}
+	return !now.Before(start) && now.Before(end)
 }
 
 // PolicyQuery defines the query conditions for listing policies
@@ -0,0 +1,293 @@
+// Copyright Project Harbor Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/astaxie/beego/validation"
+)
+
+func TestFilterMatch(t *testing.T) {
+	cases := []struct {
+		name   string
+		filter *Filter
+		value  string
+		want   bool
+	}{
+		{"glob match", &Filter{Value: "library/*"}, "library/hello-world", true},
+		{"glob mismatch", &Filter{Value: "library/*"}, "other/hello-world", false},
+		{"literal match", &Filter{MatchMode: FilterMatchModeLiteral, Value: "library/hello-world"}, "library/hello-world", true},
+		{"literal mismatch", &Filter{MatchMode: FilterMatchModeLiteral, Value: "library/hello-world"}, "library/hello-world2", false},
+		{"regex match", &Filter{MatchMode: FilterMatchModeRegex, Value: "^library/.*-debug$"}, "library/app-debug", true},
+		{"regex mismatch", &Filter{MatchMode: FilterMatchModeRegex, Value: "^library/.*-debug$"}, "library/app", false},
+		{"regex invalid pattern never matches", &Filter{MatchMode: FilterMatchModeRegex, Value: "("}, "library/app", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.filter.Match(c.value); got != c.want {
+				t.Errorf("Match(%q) = %v, want %v", c.value, got, c.want)
+			}
+		})
+	}
+}
+
+// TestFilterMatchAfterSerialization reproduces a DB/cache round trip: the
+// compiled pattern cache is unexported and dropped by JSON marshal/unmarshal,
+// so Match must lazily recompile it rather than depend on Policy.Valid
+// having populated it before serialization.
+func TestFilterMatchAfterSerialization(t *testing.T) {
+	original := &Filter{MatchMode: FilterMatchModeRegex, Value: "^library/.*-debug$"}
+	if !original.Match("library/app-debug") {
+		t.Fatal("expected the original filter to match before serialization")
+	}
+
+	data, err := json.Marshal(original)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var reloaded Filter
+	if err := json.Unmarshal(data, &reloaded); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	if !reloaded.Match("library/app-debug") {
+		t.Error("Match() = false after a serialization round trip, want true")
+	}
+}
+
+// TestFilterMatchAfterValueMutated reproduces an update flow that loads a
+// policy, overwrites an existing *Filter's Value with a new regex and
+// re-validates it on the same object before persisting: Match must pick up
+// the new pattern rather than keep matching against a stale cached one.
+func TestFilterMatchAfterValueMutated(t *testing.T) {
+	filter := &Filter{MatchMode: FilterMatchModeRegex, Value: "^library/.*-debug$"}
+
+	v := &validation.Validation{}
+	(&Policy{Name: "p", SrcRegistryID: 1, SrcNamespaces: []string{"library"}, Filters: []*Filter{filter}}).Valid(v)
+	if !filter.Match("library/app-debug") {
+		t.Fatal("expected the filter to match its original pattern")
+	}
+
+	filter.Value = "^library/.*-release$"
+	v = &validation.Validation{}
+	(&Policy{Name: "p", SrcRegistryID: 1, SrcNamespaces: []string{"library"}, Filters: []*Filter{filter}}).Valid(v)
+
+	if filter.Match("library/app-debug") {
+		t.Error("Match() = true for the old pattern after Value was mutated and re-validated, want false")
+	}
+	if !filter.Match("library/app-release") {
+		t.Error("Match() = false for the new pattern after Value was mutated and re-validated, want true")
+	}
+}
+
+func TestSelect(t *testing.T) {
+	resources := []string{
+		"library/app",
+		"library/app-debug",
+		"library/db",
+		"other/app",
+	}
+
+	filters := []*Filter{
+		{Value: "library/*"},
+		{Value: "library/*-debug", Exclude: true},
+	}
+
+	got := Select(filters, resources)
+	want := []string{"library/app", "library/db"}
+
+	if len(got) != len(want) {
+		t.Fatalf("Select() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Select() = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestTriggerWindowAllows(t *testing.T) {
+	cases := []struct {
+		name   string
+		window *TriggerWindow
+		t      time.Time
+		want   bool
+	}{
+		{
+			name:   "nil window always allows",
+			window: nil,
+			t:      time.Date(2026, 7, 28, 13, 0, 0, 0, time.UTC),
+			want:   true,
+		},
+		{
+			name:   "inside a same-day window",
+			window: &TriggerWindow{Start: "01:00", End: "05:00"},
+			t:      time.Date(2026, 7, 28, 3, 0, 0, 0, time.UTC),
+			want:   true,
+		},
+		{
+			name:   "outside a same-day window",
+			window: &TriggerWindow{Start: "01:00", End: "05:00"},
+			t:      time.Date(2026, 7, 28, 13, 0, 0, 0, time.UTC),
+			want:   false,
+		},
+		{
+			name:   "midnight-spanning window, before midnight",
+			window: &TriggerWindow{Start: "22:00", End: "06:00"},
+			t:      time.Date(2026, 7, 28, 23, 0, 0, 0, time.UTC),
+			want:   true,
+		},
+		{
+			name:   "midnight-spanning window, after midnight",
+			window: &TriggerWindow{Start: "22:00", End: "06:00"},
+			t:      time.Date(2026, 7, 28, 2, 0, 0, 0, time.UTC),
+			want:   true,
+		},
+		{
+			name:   "midnight-spanning window, outside it",
+			window: &TriggerWindow{Start: "22:00", End: "06:00"},
+			t:      time.Date(2026, 7, 28, 13, 0, 0, 0, time.UTC),
+			want:   false,
+		},
+		{
+			name:   "weekday restriction, allowed day",
+			window: &TriggerWindow{Start: "00:00", End: "23:59", Weekdays: []time.Weekday{time.Tuesday}},
+			t:      time.Date(2026, 7, 28, 13, 0, 0, 0, time.UTC), // a Tuesday
+			want:   true,
+		},
+		{
+			name:   "weekday restriction, disallowed day",
+			window: &TriggerWindow{Start: "00:00", End: "23:59", Weekdays: []time.Weekday{time.Sunday}},
+			t:      time.Date(2026, 7, 28, 13, 0, 0, 0, time.UTC), // a Tuesday
+			want:   false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.window.Allows(c.t); got != c.want {
+				t.Errorf("Allows(%v) = %v, want %v", c.t, got, c.want)
+			}
+		})
+	}
+}
+
+func TestResolveDestRepositories(t *testing.T) {
+	ctx := DestResolveContext{
+		SrcRegistryName: "hub",
+		Date:            time.Date(2026, 7, 28, 0, 0, 0, 0, time.UTC),
+	}
+
+	cases := []struct {
+		name   string
+		policy *Policy
+		src    string
+		want   []string
+	}{
+		{
+			name:   "empty template keeps the source namespace",
+			policy: &Policy{},
+			src:    "library/hello-world",
+			want:   []string{"library/hello-world"},
+		},
+		{
+			name:   "literal namespace",
+			policy: &Policy{DestNamespace: "backup"},
+			src:    "library/hello-world",
+			want:   []string{"backup/hello-world"},
+		},
+		{
+			name:   "src_namespace placeholder",
+			policy: &Policy{DestNamespace: "backup/{src_namespace}"},
+			src:    "library/hello-world",
+			want:   []string{"backup/library/hello-world"},
+		},
+		{
+			name:   "src_registry and date placeholders",
+			policy: &Policy{DestNamespace: "{src_registry}-{date}"},
+			src:    "library/hello-world",
+			want:   []string{"hub-20260728/hello-world"},
+		},
+		{
+			name:   "multiple dest namespaces fan out",
+			policy: &Policy{DestNamespaces: []string{"backup", "{src_registry}-mirror"}},
+			src:    "library/hello-world",
+			want:   []string{"backup/hello-world", "hub-mirror/hello-world"},
+		},
+		{
+			name:   "namespace-less source repository with no namespace supplied",
+			policy: &Policy{},
+			src:    "hello-world",
+			want:   []string{"hello-world"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := c.policy.ResolveDestRepositories(c.src, ctx)
+			if len(got) != len(c.want) {
+				t.Fatalf("ResolveDestRepositories() = %v, want %v", got, c.want)
+			}
+			for i := range c.want {
+				if got[i] != c.want[i] {
+					t.Fatalf("ResolveDestRepositories() = %v, want %v", got, c.want)
+				}
+			}
+		})
+	}
+}
+
+func TestPolicyValidRejectsBothDestNamespaceFields(t *testing.T) {
+	p := &Policy{
+		Name:          "p",
+		SrcRegistryID: 1,
+		SrcNamespaces: []string{"library"},
+		DestNamespace: "backup",
+		DestNamespaces: []string{
+			"mirror",
+		},
+	}
+
+	v := &validation.Validation{}
+	p.Valid(v)
+
+	found := false
+	for _, err := range v.Errors {
+		if err.Key == "dest_namespace, dest_namespaces" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Valid() errors = %v, want an error for setting both dest_namespace and dest_namespaces", v.Errors)
+	}
+}
+
+func TestSelectNoIncludeFiltersKeepsEverythingNotExcluded(t *testing.T) {
+	resources := []string{"library/app", "library/app-debug"}
+	filters := []*Filter{
+		{Value: "library/*-debug", Exclude: true},
+	}
+
+	got := Select(filters, resources)
+	want := []string{"library/app"}
+
+	if len(got) != len(want) || got[0] != want[0] {
+		t.Fatalf("Select() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright Project Harbor Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package scheduler decides when a replication policy's trigger is allowed
+// to fire.
+package scheduler
+
+import (
+	"time"
+
+	"github.com/goharbor/harbor/src/replication/ng/model"
+)
+
+// ShouldRun reports whether trigger is allowed to run at now. Manual
+// triggers are always allowed, since they're fired directly by a user
+// rather than on a schedule. Scheduled and event based triggers are gated
+// by their configured TriggerSettings.Window, if any.
+func ShouldRun(trigger *model.Trigger, now time.Time) bool {
+	if trigger == nil || trigger.Type == model.TriggerTypeManual || trigger.Settings == nil {
+		return true
+	}
+	return trigger.Settings.Window.Allows(now)
+}
@@ -0,0 +1,66 @@
+// Copyright Project Harbor Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/goharbor/harbor/src/replication/ng/model"
+)
+
+func TestShouldRun(t *testing.T) {
+	offPeak := &model.TriggerWindow{Start: "22:00", End: "06:00"}
+
+	cases := []struct {
+		name    string
+		trigger *model.Trigger
+		now     time.Time
+		want    bool
+	}{
+		{
+			name:    "manual trigger always runs",
+			trigger: &model.Trigger{Type: model.TriggerTypeManual},
+			now:     time.Date(2026, 7, 28, 13, 0, 0, 0, time.UTC),
+			want:    true,
+		},
+		{
+			name:    "scheduled trigger without a window always runs",
+			trigger: &model.Trigger{Type: model.TriggerTypeScheduled, Settings: &model.TriggerSettings{Cron: "0 0 * * *"}},
+			now:     time.Date(2026, 7, 28, 13, 0, 0, 0, time.UTC),
+			want:    true,
+		},
+		{
+			name:    "scheduled trigger inside the off-peak window",
+			trigger: &model.Trigger{Type: model.TriggerTypeScheduled, Settings: &model.TriggerSettings{Window: offPeak}},
+			now:     time.Date(2026, 7, 28, 23, 0, 0, 0, time.UTC),
+			want:    true,
+		},
+		{
+			name:    "scheduled trigger outside the off-peak window",
+			trigger: &model.Trigger{Type: model.TriggerTypeScheduled, Settings: &model.TriggerSettings{Window: offPeak}},
+			now:     time.Date(2026, 7, 28, 13, 0, 0, 0, time.UTC),
+			want:    false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := ShouldRun(c.trigger, c.now); got != c.want {
+				t.Errorf("ShouldRun() = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
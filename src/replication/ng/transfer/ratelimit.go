@@ -0,0 +1,68 @@
+// Copyright Project Harbor Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package transfer pulls/pushes blobs between registries on behalf of a
+// replication policy, honoring the policy's trigger settings along the way.
+package transfer
+
+import (
+	"io"
+	"time"
+)
+
+// rateLimitedReader wraps a reader and throttles Read so the long run
+// average throughput doesn't exceed limit bytes/second.
+type rateLimitedReader struct {
+	r     io.Reader
+	limit int64 // bytes/second; <= 0 means unlimited
+
+	windowStart  time.Time
+	readInWindow int64
+}
+
+// NewRateLimitedReader wraps r so reads honor limitBytesPerSec, the
+// bandwidth limit configured on a replication trigger
+// (model.TriggerSettings.BandwidthLimit). A non-positive limit disables
+// throttling and returns r unchanged.
+func NewRateLimitedReader(r io.Reader, limitBytesPerSec int64) io.Reader {
+	if limitBytesPerSec <= 0 {
+		return r
+	}
+	return &rateLimitedReader{r: r, limit: limitBytesPerSec}
+}
+
+func (r *rateLimitedReader) Read(p []byte) (int, error) {
+	now := time.Now()
+	if r.windowStart.IsZero() || now.Sub(r.windowStart) >= time.Second {
+		r.windowStart = now
+		r.readInWindow = 0
+	}
+
+	if r.readInWindow >= r.limit {
+		sleep := time.Second - now.Sub(r.windowStart)
+		if sleep > 0 {
+			time.Sleep(sleep)
+		}
+		r.windowStart = time.Now()
+		r.readInWindow = 0
+	}
+
+	if remaining := r.limit - r.readInWindow; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+
+	n, err := r.r.Read(p)
+	r.readInWindow += int64(n)
+	return n, err
+}
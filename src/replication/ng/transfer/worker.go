@@ -0,0 +1,82 @@
+// Copyright Project Harbor Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transfer
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	"github.com/goharbor/harbor/src/replication/ng/model"
+	"github.com/goharbor/harbor/src/replication/ng/scheduler"
+)
+
+// ErrOutsideWindow is returned by Transfer when policy's trigger has a
+// window configured and it doesn't allow running at the time passed in.
+var ErrOutsideWindow = errors.New("transfer: outside the trigger's allowed window")
+
+// Pusher pushes a blob to destRepository. It's implemented by the registry
+// client a Worker uses to actually talk to the destination registry.
+type Pusher interface {
+	Push(destRepository string, blob io.Reader) error
+}
+
+// Worker transfers blobs on behalf of a replication policy, honoring the
+// policy's trigger settings while doing so.
+type Worker struct {
+	Pusher Pusher
+}
+
+// Push reads blob and pushes it to destRepository, rate limited according to
+// policy.Trigger.Settings.BandwidthLimit so large policies don't saturate
+// the link. Push doesn't consult the trigger window itself; callers that
+// need it honored should go through Transfer, or call scheduler.ShouldRun
+// themselves.
+func (w *Worker) Push(policy *model.Policy, destRepository string, blob io.Reader) error {
+	if policy.Trigger != nil && policy.Trigger.Settings != nil {
+		blob = NewRateLimitedReader(blob, policy.Trigger.Settings.BandwidthLimit)
+	}
+	return w.Pusher.Push(destRepository, blob)
+}
+
+// BlobOpener opens a fresh reader for the blob being transferred. Transfer
+// calls it once per resolved destination repository, since a pulled blob is
+// typically staged locally before being pushed out to each destination.
+type BlobOpener func() (io.ReadCloser, error)
+
+// Transfer resolves every destination repository srcRepository maps to
+// under policy (see model.Policy.ResolveDestRepositories) and pushes the
+// blob, opened fresh via open for each one, to all of them. now is checked
+// against policy.Trigger's window (see scheduler.ShouldRun); outside it,
+// Transfer does nothing and returns ErrOutsideWindow instead of pulling or
+// pushing any blobs.
+func (w *Worker) Transfer(policy *model.Policy, srcRepository string, ctx model.DestResolveContext, open BlobOpener, now time.Time) error {
+	if !scheduler.ShouldRun(policy.Trigger, now) {
+		return ErrOutsideWindow
+	}
+
+	for _, destRepository := range policy.ResolveDestRepositories(srcRepository, ctx) {
+		blob, err := open()
+		if err != nil {
+			return err
+		}
+		err = w.Push(policy, destRepository, blob)
+		blob.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
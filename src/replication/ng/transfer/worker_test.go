@@ -0,0 +1,155 @@
+// Copyright Project Harbor Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transfer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/goharbor/harbor/src/replication/ng/model"
+)
+
+type recordingPusher struct {
+	destRepository string
+	blob           []byte
+
+	pushedTo []string
+}
+
+func (p *recordingPusher) Push(destRepository string, blob io.Reader) error {
+	data, err := ioutil.ReadAll(blob)
+	if err != nil {
+		return err
+	}
+	p.destRepository = destRepository
+	p.blob = data
+	p.pushedTo = append(p.pushedTo, destRepository)
+	return nil
+}
+
+type closableReader struct {
+	*bytes.Reader
+}
+
+func (closableReader) Close() error { return nil }
+
+func TestWorkerPush(t *testing.T) {
+	pusher := &recordingPusher{}
+	worker := &Worker{Pusher: pusher}
+	policy := &model.Policy{
+		Trigger: &model.Trigger{
+			Type:     model.TriggerTypeScheduled,
+			Settings: &model.TriggerSettings{BandwidthLimit: 1024},
+		},
+	}
+
+	content := []byte("hello world")
+	if err := worker.Push(policy, "library/hello-world", bytes.NewReader(content)); err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+	if pusher.destRepository != "library/hello-world" {
+		t.Errorf("destRepository = %q, want %q", pusher.destRepository, "library/hello-world")
+	}
+	if !bytes.Equal(pusher.blob, content) {
+		t.Errorf("blob = %q, want %q", pusher.blob, content)
+	}
+}
+
+func TestWorkerTransferFansOutToResolvedDestinations(t *testing.T) {
+	pusher := &recordingPusher{}
+	worker := &Worker{Pusher: pusher}
+	policy := &model.Policy{
+		DestNamespaces: []string{"backup", "mirror"},
+	}
+	content := []byte("hello world")
+	open := func() (io.ReadCloser, error) {
+		return closableReader{bytes.NewReader(content)}, nil
+	}
+
+	now := time.Date(2026, 7, 28, 13, 0, 0, 0, time.UTC)
+	err := worker.Transfer(policy, "library/hello-world", model.DestResolveContext{}, open, now)
+	if err != nil {
+		t.Fatalf("Transfer() error = %v", err)
+	}
+
+	want := []string{"backup/hello-world", "mirror/hello-world"}
+	if len(pusher.pushedTo) != len(want) {
+		t.Fatalf("pushed to %v, want %v", pusher.pushedTo, want)
+	}
+	for i := range want {
+		if pusher.pushedTo[i] != want[i] {
+			t.Fatalf("pushed to %v, want %v", pusher.pushedTo, want)
+		}
+	}
+}
+
+func TestWorkerTransferSkipsOutsideTheTriggerWindow(t *testing.T) {
+	pusher := &recordingPusher{}
+	worker := &Worker{Pusher: pusher}
+	policy := &model.Policy{
+		Trigger: &model.Trigger{
+			Type: model.TriggerTypeScheduled,
+			Settings: &model.TriggerSettings{
+				Window: &model.TriggerWindow{Start: "22:00", End: "06:00"},
+			},
+		},
+	}
+	open := func() (io.ReadCloser, error) {
+		t.Fatal("open() should not be called outside the trigger window")
+		return nil, nil
+	}
+
+	// 13:00 falls outside the 22:00-06:00 off-peak window.
+	now := time.Date(2026, 7, 28, 13, 0, 0, 0, time.UTC)
+	err := worker.Transfer(policy, "library/hello-world", model.DestResolveContext{}, open, now)
+	if !errors.Is(err, ErrOutsideWindow) {
+		t.Fatalf("Transfer() error = %v, want %v", err, ErrOutsideWindow)
+	}
+	if len(pusher.pushedTo) != 0 {
+		t.Errorf("pushedTo = %v, want no pushes", pusher.pushedTo)
+	}
+}
+
+func TestRateLimitedReaderUnlimited(t *testing.T) {
+	content := []byte("no throttling here")
+	r := NewRateLimitedReader(bytes.NewReader(content), 0)
+
+	got, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("ReadAll() = %q, want %q", got, content)
+	}
+}
+
+func TestRateLimitedReaderPreservesContent(t *testing.T) {
+	// limit is kept well above len(content) so the whole read completes
+	// within a single window and the test doesn't have to sleep.
+	content := bytes.Repeat([]byte("x"), 4096)
+	r := NewRateLimitedReader(bytes.NewReader(content), 1<<20)
+
+	got, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Error("ReadAll() did not return the same bytes that were written")
+	}
+}